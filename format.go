@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hop describes one response in a redirect chain: the request that was
+// made and the status code the server returned for it.
+type Hop struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Method     string      `json:"method"`
+	Headers    http.Header `json:"headers,omitempty"`
+}
+
+// Site contains the request URL, HTTP status code, response URL, and the
+// redirect chain and timing information gathered while checking it.
+type Site struct {
+	RequestURL   url.URL
+	StatusCode   int
+	ResponseURL  *url.URL
+	Redirects    []Hop
+	Duration     time.Duration
+	ErrorClass   string
+	ErrorMessage string
+	// Unchanged is set when this outcome was resolved from a cached state
+	// entry (either skipped as fresh, or answered with a 304) rather than
+	// from a newly observed response.
+	Unchanged bool
+	// FinalHeaders holds the response headers of the last hop made while
+	// checking this URL - the terminal response itself, not one of the
+	// redirects that led to it. It is not part of any output format; it
+	// exists only so retryAfter and entryFromSite can read Retry-After,
+	// ETag, and Last-Modified without those headers having to be smuggled
+	// in through Redirects.
+	FinalHeaders http.Header
+}
+
+func (s *Site) String() string {
+	var line = fmt.Sprintf("%s,%d,", s.RequestURL.String(), s.StatusCode)
+	if s.ResponseURL != nil {
+		line += s.ResponseURL.String()
+	}
+	return line
+}
+
+// outputFormat identifies one of the writer implementations selectable
+// through the `-format` flag.
+type outputFormat string
+
+const (
+	formatPlain outputFormat = "plain"
+	formatCSV   outputFormat = "csv"
+	formatJSON  outputFormat = "json"
+	formatJSONL outputFormat = "jsonl"
+	formatSARIF outputFormat = "sarif"
+)
+
+// parseOutputFormat validates a `-format` flag value.
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch outputFormat(s) {
+	case formatPlain, formatCSV, formatJSON, formatJSONL, formatSARIF:
+		return outputFormat(s), nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", s)
+	}
+}
+
+// record is the structured representation of a Site shared by the
+// non-plain output formats.
+type record struct {
+	RequestURL   string `json:"request_url"`
+	FinalURL     string `json:"final_url,omitempty"`
+	StatusCode   int    `json:"status_code"`
+	Redirects    []Hop  `json:"redirects,omitempty"`
+	DurationMS   int64  `json:"duration_ms"`
+	ErrorClass   string `json:"error_class,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	Unchanged    bool   `json:"unchanged,omitempty"`
+}
+
+func toRecord(s *Site) record {
+	var rec = record{
+		RequestURL:   s.RequestURL.String(),
+		StatusCode:   s.StatusCode,
+		Redirects:    s.Redirects,
+		DurationMS:   s.Duration.Milliseconds(),
+		ErrorClass:   s.ErrorClass,
+		ErrorMessage: s.ErrorMessage,
+		Unchanged:    s.Unchanged,
+	}
+	if s.ResponseURL != nil {
+		rec.FinalURL = s.ResponseURL.String()
+	}
+	return rec
+}
+
+func writeWorker(wg *sync.WaitGroup, result <-chan *Site, format outputFormat) {
+	defer wg.Done()
+	switch format {
+	case formatCSV:
+		writeCSV(result)
+	case formatJSON:
+		writeJSON(result)
+	case formatJSONL:
+		writeJSONL(result)
+	case formatSARIF:
+		writeSARIF(result)
+	default:
+		writePlain(result)
+	}
+}
+
+func writePlain(result <-chan *Site) {
+	for r := range result {
+		os.Stdout.WriteString(r.String() + "\n")
+	}
+}
+
+func writeCSV(result <-chan *Site) {
+	var w = csv.NewWriter(os.Stdout)
+	defer w.Flush()
+	w.Write([]string{"request_url", "final_url", "status_code", "redirects", "duration_ms", "error_class", "error_message", "unchanged"})
+	for r := range result {
+		var rec = toRecord(r)
+		w.Write([]string{
+			rec.RequestURL,
+			rec.FinalURL,
+			fmt.Sprintf("%d", rec.StatusCode),
+			formatRedirects(rec.Redirects),
+			fmt.Sprintf("%d", rec.DurationMS),
+			rec.ErrorClass,
+			rec.ErrorMessage,
+			fmt.Sprintf("%t", rec.Unchanged),
+		})
+	}
+}
+
+func formatRedirects(hops []Hop) string {
+	var parts = make([]string, len(hops))
+	for i, h := range hops {
+		parts[i] = fmt.Sprintf("%s:%d", h.URL, h.StatusCode)
+	}
+	return strings.Join(parts, "|")
+}
+
+func writeJSON(result <-chan *Site) {
+	var records = make([]record, 0)
+	for r := range result {
+		records = append(records, toRecord(r))
+	}
+	json.NewEncoder(os.Stdout).Encode(records)
+}
+
+func writeJSONL(result <-chan *Site) {
+	var enc = json.NewEncoder(os.Stdout)
+	for r := range result {
+		enc.Encode(toRecord(r))
+	}
+}
+
+// sarifDoc is a minimal subset of the SARIF 2.1.0 schema - just enough
+// structure for checkurls results to be consumed by tools that expect
+// SARIF input.
+type sarifDoc struct {
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID  string       `json:"ruleId"`
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+func writeSARIF(result <-chan *Site) {
+	var run = sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "checkurls"}}}
+	for r := range result {
+		var rec = toRecord(r)
+		var level = "note"
+		if rec.ErrorClass != "" || rec.StatusCode >= 400 {
+			level = "error"
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID: "checkurls/broken-link",
+			Level:  level,
+			Message: sarifMessage{
+				Text: fmt.Sprintf("%s -> %d %s", rec.RequestURL, rec.StatusCode, rec.FinalURL),
+			},
+		})
+	}
+	var doc = sarifDoc{Version: "2.1.0", Runs: []sarifRun{run}}
+	json.NewEncoder(os.Stdout).Encode(doc)
+}
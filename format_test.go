@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, f := range []string{"plain", "csv", "json", "jsonl", "sarif"} {
+		if got, err := parseOutputFormat(f); err != nil || got != outputFormat(f) {
+			t.Errorf("parseOutputFormat(%q) = %v, %v", f, got, err)
+		}
+	}
+	if _, err := parseOutputFormat("yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestToRecord(t *testing.T) {
+	var responseURL, _ = url.Parse("http://example.com/final")
+	var s = &Site{
+		RequestURL:  url.URL{Scheme: "http", Host: "example.com", Path: "/"},
+		StatusCode:  200,
+		ResponseURL: responseURL,
+		Redirects:   []Hop{{URL: "http://example.com/", StatusCode: 301, Method: "GET"}},
+		Duration:    250 * time.Millisecond,
+	}
+	var rec = toRecord(s)
+	if rec.RequestURL != "http://example.com/" {
+		t.Errorf("RequestURL = %q", rec.RequestURL)
+	}
+	if rec.FinalURL != "http://example.com/final" {
+		t.Errorf("FinalURL = %q", rec.FinalURL)
+	}
+	if rec.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", rec.StatusCode)
+	}
+	if rec.DurationMS != 250 {
+		t.Errorf("DurationMS = %d, want 250", rec.DurationMS)
+	}
+	if len(rec.Redirects) != 1 || rec.Redirects[0].StatusCode != 301 {
+		t.Errorf("Redirects = %+v", rec.Redirects)
+	}
+}
+
+func TestToRecordWithoutResponseURL(t *testing.T) {
+	var rec = toRecord(&Site{ErrorClass: classDNS, ErrorMessage: "no such host"})
+	if rec.FinalURL != "" {
+		t.Errorf("FinalURL = %q, want empty when ResponseURL is nil", rec.FinalURL)
+	}
+	if rec.ErrorClass != classDNS || rec.ErrorMessage != "no such host" {
+		t.Errorf("rec = %+v", rec)
+	}
+}
+
+func TestFormatRedirects(t *testing.T) {
+	var hops = []Hop{
+		{URL: "http://example.com/", StatusCode: 301},
+		{URL: "https://example.com/", StatusCode: 302},
+	}
+	var want = "http://example.com/:301|https://example.com/:302"
+	if got := formatRedirects(hops); got != want {
+		t.Errorf("formatRedirects = %q, want %q", got, want)
+	}
+	if got := formatRedirects(nil); got != "" {
+		t.Errorf("formatRedirects(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxSitemapDepth caps how deeply fetchSitemapURLs recurses into nested
+// sitemap indexes, guarding against a cyclic or malicious sitemap.
+const maxSitemapDepth = 5
+
+// hrefPattern extracts the href attribute of anchor tags from raw HTML.
+// A full parser isn't worth the dependency for link extraction alone.
+var hrefPattern = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*"([^"]*)"`)
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+// expandInputLine turns one line of plain URL-list input into the URLs to
+// check: fully-qualified URLs (with a scheme) are used as-is, and bare
+// hosts - with or without a path, query, or fragment already attached -
+// are expanded into both http and https variants. Only a genuinely bare
+// host gets a synthesized "/" path; an existing path/query/fragment is
+// preserved exactly as written instead of having "/" appended to it.
+func expandInputLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	if u, err := url.Parse(line); err == nil && u.Scheme != "" && u.Host != "" {
+		return []string{line}
+	}
+	// Parse as an authority-relative URL so the host is split from any
+	// path/query/fragment that follows it, rather than treating the whole
+	// line as an opaque hostname.
+	u, err := url.Parse("//" + line)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	var out = make([]string, 0, len(protocols))
+	for _, prot := range protocols {
+		u.Scheme = prot
+		out = append(out, u.String())
+	}
+	return out
+}
+
+// fetchSitemapURLs fetches target, recursively following <sitemapindex>
+// entries, and returns the <loc> URLs found in the leaf <urlset> documents.
+// Requests are made with c and opts, so the configured timeout, user agent,
+// and extra headers are honored just as they are when checking URLs.
+func fetchSitemapURLs(c *http.Client, opts checkOptions, target string) []string {
+	return fetchSitemapURLsDepth(c, opts, target, maxSitemapDepth)
+}
+
+func fetchSitemapURLsDepth(c *http.Client, opts checkOptions, target string, depth int) []string {
+	if depth <= 0 {
+		os.Stderr.WriteString("sitemap recursion limit reached at " + target + "\n")
+		return nil
+	}
+	data, err := fetchBody(c, opts, target)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return nil
+	}
+	var index sitemapIndex
+	if err := xml.Unmarshal(data, &index); err == nil {
+		var out []string
+		for _, s := range index.Sitemaps {
+			out = append(out, fetchSitemapURLsDepth(c, opts, s.Loc, depth-1)...)
+		}
+		return out
+	}
+	var set sitemapURLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		os.Stderr.WriteString("unrecognized sitemap at " + target + ": " + err.Error() + "\n")
+		return nil
+	}
+	var out = make([]string, 0, len(set.URLs))
+	for _, u := range set.URLs {
+		out = append(out, u.Loc)
+	}
+	return out
+}
+
+// fetchHTMLLinks fetches target and returns every <a href> found in it,
+// resolved against target so relative links become absolute URLs. Requests
+// are made with c and opts, so the configured timeout, user agent, and
+// extra headers are honored just as they are when checking URLs.
+func fetchHTMLLinks(c *http.Client, opts checkOptions, target string) []string {
+	data, err := fetchBody(c, opts, target)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return nil
+	}
+	base, err := url.Parse(target)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		return nil
+	}
+	var matches = hrefPattern.FindAllSubmatch(data, -1)
+	var out = make([]string, 0, len(matches))
+	for _, m := range matches {
+		ref, err := url.Parse(html.UnescapeString(string(m[1])))
+		if err != nil {
+			continue
+		}
+		out = append(out, base.ResolveReference(ref).String())
+	}
+	return out
+}
+
+func fetchBody(c *http.Client, opts checkOptions, target string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyRequestOptions(req, opts)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandInputLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{
+			name: "fully qualified URL is passed through unchanged",
+			line: "https://example.com/path?q=1",
+			want: []string{"https://example.com/path?q=1"},
+		},
+		{
+			name: "bare host gets both schemes and a synthesized path",
+			line: "example.com",
+			want: []string{"http://example.com/", "https://example.com/"},
+		},
+		{
+			name: "bare host with path and query is preserved, not mangled",
+			line: "example.com/search?q=foo",
+			want: []string{"http://example.com/search?q=foo", "https://example.com/search?q=foo"},
+		},
+		{
+			name: "surrounding whitespace is trimmed",
+			line: "  example.com/path  \n",
+			want: []string{"http://example.com/path", "https://example.com/path"},
+		},
+		{
+			name: "blank line yields nothing",
+			line: "   ",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandInputLine(tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandInputLine(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
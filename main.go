@@ -4,18 +4,13 @@ import (
 	"bufio"
 	"errors"
 	"flag"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"sync"
-)
-
-const (
-	// NumCheckers contains the number of workers that are used to check URLs.
-	NumCheckers = 5
+	"time"
 )
 
 var (
@@ -56,37 +51,71 @@ var (
 	}
 )
 
-// Site contains the request URL, HTTP status code and response URL.
-type Site struct {
-	RequestURL  url.URL
-	StatusCode  int
-	ResponseURL *url.URL
-}
-
-func (s *Site) String() string {
-	var line = fmt.Sprintf("%s,%d,", s.RequestURL.String(), s.StatusCode)
-	if s.ResponseURL != nil {
-		line += s.ResponseURL.String()
-	}
-	return line
-}
-
 func main() {
+	var formatFlag = flag.String("format", string(formatPlain), "output format: plain, csv, json, jsonl, sarif")
+	var maxRedirectsFlag = flag.Int("max-redirects", 10, "maximum number of redirects to follow per URL")
+	var concurrencyFlag = flag.Int("concurrency", 5, "number of worker goroutines used to check URLs")
+	var rpsFlag = flag.Float64("rps", 0, "global rate limit in requests per second (0 = unlimited)")
+	var perHostConcurrencyFlag = flag.Int("per-host-concurrency", 0, "maximum concurrent requests per host (0 = unlimited)")
+	var methodFlag = flag.String("method", methodAuto, "HTTP method to use: GET, HEAD, or AUTO (HEAD with GET fallback)")
+	var userAgentFlag = flag.String("user-agent", "checkurls/1.0", "User-Agent header to send")
+	var timeoutFlag = flag.Duration("timeout", 10*time.Second, "per-request timeout")
+	var headersFlag headerList
+	flag.Var(&headersFlag, "header", "extra request header in K:V form, may be repeated")
+	var retriesFlag = flag.Int("retries", 0, "number of times to retry a retryable outcome")
+	var retryBackoffFlag = flag.Duration("retry-backoff", 200*time.Millisecond, "base exponential backoff between retries, with jitter")
+	var retryOnFlag = flag.String("retry-on", "429,502,503,504,connection-reset", "comma-separated status codes and/or \"connection-reset\" to retry")
+	var stateFlag = flag.String("state", "", "file to persist per-URL check state in, for incremental runs")
+	var resumeFlag = flag.Bool("resume", false, "skip URLs whose -state entry is fresher than -max-age")
+	var maxAgeFlag = flag.Duration("max-age", 24*time.Hour, "how fresh a -resume entry must be to skip rechecking")
+	var inputFlag = flag.String("input", "", "input source: sitemap:URL, html:URL, hn:USER, or empty to read one URL/host per line")
+	var ignoreRobotsFlag = flag.Bool("ignore-robots", false, "check URLs even where robots.txt disallows it")
 	flag.Parse()
+	format, err := parseOutputFormat(*formatFlag)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	retryStatuses, retryConnReset, err := parseRetryOn(*retryOnFlag)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var retry = retryOptions{
+		retries:   *retriesFlag,
+		backoff:   *retryBackoffFlag,
+		statuses:  retryStatuses,
+		connReset: retryConnReset,
+	}
+	state, err := openStateStore(*stateFlag)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	defer state.Close()
+	var opts = checkOptions{
+		method:    strings.ToUpper(*methodFlag),
+		userAgent: *userAgentFlag,
+		headers:   headersFlag.headers,
+	}
 	// start result writer
 	var writerGroup sync.WaitGroup
 	var result = make(chan *Site)
 	writerGroup.Add(1)
-	go writeWorker(&writerGroup, result)
+	go writeWorker(&writerGroup, result, format)
 	// start url reader
+	var fetchClient = &http.Client{Timeout: *timeoutFlag}
 	var work = make(chan string)
-	go readWorker(work)
+	go readWorker(work, *inputFlag, fetchClient, opts)
 	// start checkers
-	var checkRedirect = StopOnFirstRedirect
+	var checkRedirect = withMaxRedirects(*maxRedirectsFlag, FollowAllRedirects)
+	var rate = newTokenBucket(*rpsFlag)
+	var hosts = newHostGate(*perHostConcurrencyFlag)
+	var robots = newRobotsCache(fetchClient, opts)
 	var workerGroup sync.WaitGroup
-	for i := 0; i < NumCheckers; i++ {
+	for i := 0; i < *concurrencyFlag; i++ {
 		workerGroup.Add(1)
-		go checkWorker(&workerGroup, work, result, checkRedirect)
+		go checkWorker(&workerGroup, work, result, checkRedirect, rate, hosts, *timeoutFlag, opts, retry, state, *resumeFlag, *maxAgeFlag, robots, *ignoreRobotsFlag)
 	}
 	// wait for all checkers to finish
 	workerGroup.Wait()
@@ -95,8 +124,28 @@ func main() {
 	writerGroup.Wait()
 }
 
-func readWorker(work chan<- string) {
+func readWorker(work chan<- string, input string, c *http.Client, opts checkOptions) {
 	defer close(work)
+	switch {
+	case strings.HasPrefix(input, "sitemap:"):
+		for _, site := range fetchSitemapURLs(c, opts, strings.TrimPrefix(input, "sitemap:")) {
+			work <- site
+		}
+	case strings.HasPrefix(input, "html:"):
+		for _, site := range fetchHTMLLinks(c, opts, strings.TrimPrefix(input, "html:")) {
+			work <- site
+		}
+	case strings.HasPrefix(input, "hn:"):
+		var user = strings.TrimPrefix(input, "hn:")
+		for _, site := range fetchHTMLLinks(c, opts, "https://news.ycombinator.com/submitted?id="+user) {
+			work <- site
+		}
+	default:
+		readLineWorker(work)
+	}
+}
+
+func readLineWorker(work chan<- string) {
 	var source io.Reader
 	if flag.NArg() < 1 {
 		source = os.Stdin
@@ -110,11 +159,9 @@ func readWorker(work chan<- string) {
 	}
 	lineReader := bufio.NewReader(source)
 	for {
-		site, err := lineReader.ReadString('\n')
-		if len(site) > 0 {
-			for _, prot := range protocols {
-				work <- formatURL(prot, strings.Trim(site, " \t\r\n"))
-			}
+		line, err := lineReader.ReadString('\n')
+		for _, site := range expandInputLine(line) {
+			work <- site
 		}
 		if err != nil {
 			break
@@ -122,52 +169,107 @@ func readWorker(work chan<- string) {
 	}
 }
 
-func checkWorker(wg *sync.WaitGroup, work <-chan string, result chan<- *Site, check Redirector) {
+func checkWorker(wg *sync.WaitGroup, work <-chan string, result chan<- *Site, check Redirector, rate *tokenBucket, hosts *hostGate, timeout time.Duration, opts checkOptions, retry retryOptions, state *stateStore, resume bool, maxAge time.Duration, robots *robotsCache, ignoreRobots bool) {
 	defer wg.Done()
 	var c http.Client
 	c.CheckRedirect = check
+	c.Transport = &recordingTransport{}
+	c.Timeout = timeout
 	for site := range work {
-		r, err := testURL(&c, site)
-		if err == nil {
-			result <- r
-		} else {
+		u, err := url.Parse(site)
+		if err != nil {
 			os.Stderr.WriteString(err.Error() + "\n")
+			continue
+		}
+		var host = u.Host
+		if !ignoreRobots {
+			var rules = robots.rulesFor(u)
+			if !rules.allowed(u.Path) {
+				os.Stderr.WriteString("robots.txt disallows " + site + "\n")
+				continue
+			}
+			robots.wait(host, rules.crawlDelay)
 		}
+		entry, hasEntry := state.lookup(site)
+		if resume && hasEntry && time.Since(entry.CheckedAt) < maxAge {
+			result <- &Site{RequestURL: parsedURL(site), StatusCode: entry.StatusCode, Unchanged: true}
+			continue
+		}
+		var siteOpts = opts
+		if hasEntry {
+			siteOpts.headers = conditionalHeaders(opts.headers, entry)
+		}
+		r, err := checkWithRetry(&c, site, siteOpts, retry, rate, hosts, host)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			continue
+		}
+		if hasEntry && r.StatusCode == http.StatusNotModified {
+			r.StatusCode = entry.StatusCode
+			r.Unchanged = true
+		}
+		if r.ErrorClass == "" {
+			state.record(entryFromSite(site, r))
+		}
+		result <- r
 	}
 }
 
-func writeWorker(wg *sync.WaitGroup, result <-chan *Site) {
-	defer wg.Done()
-	for r := range result {
-		os.Stdout.WriteString(r.String() + "\n")
+// parsedURL parses site, returning the zero url.URL if it cannot be
+// parsed - used only for reporting a cache hit, where the URL has
+// already been validated by an earlier check.
+func parsedURL(site string) url.URL {
+	if u, err := url.Parse(site); err == nil {
+		return *u
 	}
+	return url.URL{}
 }
 
-func testURL(c *http.Client, site string) (*Site, error) {
-	resp, err := c.Get(site)
-	if err != nil {
-		switch e := err.(type) {
-		case *url.Error:
-			if e.Err == errDone {
-				// just an errDone, continue
-				break
-			}
-			// an unexpected error
-			return nil, err
-		default:
-			// an unexpected error
-			return nil, err
+func testURL(c *http.Client, site string, opts checkOptions) (*Site, error) {
+	var method = opts.method
+	if method == "" {
+		method = methodAuto
+	}
+	var probeMethod = method
+	if probeMethod == methodAuto {
+		probeMethod = http.MethodHead
+	}
+	resp, req, hops, elapsed, err := doRequest(c, probeMethod, site, opts)
+	if req == nil {
+		// an unexpected error: the URL itself could not be parsed
+		return nil, err
+	}
+	if err != nil && !isRedirectStop(err) {
+		var chain, finalHeaders = splitHops(hops)
+		return &Site{RequestURL: *req.URL, Duration: elapsed, Redirects: chain, FinalHeaders: finalHeaders, ErrorClass: classifyError(err), ErrorMessage: err.Error()}, nil
+	}
+	if method == methodAuto && resp != nil && needsGETFallback(resp) {
+		// The HEAD probe is discarded entirely once a GET fallback is
+		// needed: it was never part of the chain that led to the answer
+		// being reported, so its hops (redirects or not) must not leak
+		// into the result's Redirects.
+		var fallbackResp, fallbackReq, fallbackHops, fallbackElapsed, fallbackErr = doRequest(c, http.MethodGet, site, opts)
+		elapsed += fallbackElapsed
+		if fallbackReq == nil {
+			return nil, fallbackErr
+		}
+		if fallbackErr != nil && !isRedirectStop(fallbackErr) {
+			var chain, finalHeaders = splitHops(fallbackHops)
+			return &Site{RequestURL: *fallbackReq.URL, Duration: elapsed, Redirects: chain, FinalHeaders: finalHeaders, ErrorClass: classifyError(fallbackErr), ErrorMessage: fallbackErr.Error()}, nil
 		}
+		resp, req = fallbackResp, fallbackReq
+		hops = fallbackHops
 	}
 	var result Site
-	result.RequestURL = *resp.Request.URL
-	result.StatusCode = resp.StatusCode
-	if loc, err := resp.Location(); err == nil {
-		result.ResponseURL = loc
+	result.RequestURL = *req.URL
+	result.Duration = elapsed
+	result.Redirects, result.FinalHeaders = splitHops(hops)
+	if resp != nil {
+		result.RequestURL = *resp.Request.URL
+		result.StatusCode = resp.StatusCode
+		if loc, err := resp.Location(); err == nil {
+			result.ResponseURL = loc
+		}
 	}
 	return &result, nil
 }
-
-func formatURL(protocol string, site string) string {
-	return protocol + "://" + site + "/"
-}
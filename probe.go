@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	methodAuto = "AUTO"
+)
+
+// checkOptions carries the per-request settings that apply to every probe
+// a checkWorker makes: which HTTP method to use, what identifying
+// information to send, and how long to wait for a response.
+type checkOptions struct {
+	method    string
+	userAgent string
+	headers   http.Header
+}
+
+// headerList implements flag.Value so that `-header K:V` can be repeated
+// on the command line to build up a set of extra request headers.
+type headerList struct {
+	headers http.Header
+}
+
+func (h *headerList) String() string {
+	if h == nil {
+		return ""
+	}
+	var parts []string
+	for k, vs := range h.headers {
+		for _, v := range vs {
+			parts = append(parts, k+":"+v)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (h *headerList) Set(value string) error {
+	var name, val, ok = strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("invalid -header %q, expected K:V", value)
+	}
+	if h.headers == nil {
+		h.headers = make(http.Header)
+	}
+	h.headers.Add(strings.TrimSpace(name), strings.TrimSpace(val))
+	return nil
+}
+
+// applyRequestOptions sets the User-Agent and extra headers configured in
+// opts on req, the way every outbound request checkurls makes should.
+func applyRequestOptions(req *http.Request, opts checkOptions) {
+	if opts.userAgent != "" {
+		req.Header.Set("User-Agent", opts.userAgent)
+	}
+	for k, vs := range opts.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+}
+
+// doRequest issues a single HTTP request for site, using the given method
+// and options, and reports the request actually sent, the redirect chain
+// it followed, how long it took, and any error.
+func doRequest(c *http.Client, method string, site string, opts checkOptions) (*http.Response, *http.Request, []Hop, time.Duration, error) {
+	req, err := http.NewRequest(method, site, nil)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	applyRequestOptions(req, opts)
+	var hops []Hop
+	req = withHops(req, &hops)
+	var start = time.Now()
+	resp, err := c.Do(req)
+	return resp, req, hops, time.Since(start), err
+}
+
+// isRedirectStop reports whether err is the sentinel error produced when a
+// Redirector deliberately stopped following redirects, rather than an
+// actual request failure.
+func isRedirectStop(err error) bool {
+	e, ok := err.(*url.Error)
+	return ok && e.Err == errDone
+}
+
+// needsGETFallback decides whether a HEAD probe made in AUTO mode should be
+// retried with GET: servers that don't implement HEAD either reject it
+// outright, or return a 200 with no body where a GET would have one.
+func needsGETFallback(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden:
+		return true
+	}
+	return resp.StatusCode == http.StatusOK && resp.ContentLength == 0
+}
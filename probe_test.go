@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestDoRequestSetsUserAgentAndHeaders(t *testing.T) {
+	var gotUA, gotExtra string
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotExtra = r.Header.Get("X-Extra")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var opts = checkOptions{userAgent: "test-agent/1.0", headers: http.Header{"X-Extra": []string{"yes"}}}
+	resp, req, _, _, err := doRequest(http.DefaultClient, http.MethodGet, server.URL, opts)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	defer resp.Body.Close()
+	if gotUA != "test-agent/1.0" {
+		t.Errorf("User-Agent = %q, want test-agent/1.0", gotUA)
+	}
+	if gotExtra != "yes" {
+		t.Errorf("X-Extra = %q, want yes", gotExtra)
+	}
+	if req.Method != http.MethodGet {
+		t.Errorf("method = %q, want GET", req.Method)
+	}
+}
+
+func TestNeedsGETFallback(t *testing.T) {
+	tests := []struct {
+		status        int
+		contentLength int64
+		want          bool
+	}{
+		{http.StatusMethodNotAllowed, 100, true},
+		{http.StatusNotImplemented, 100, true},
+		{http.StatusForbidden, 100, true},
+		{http.StatusOK, 0, true},
+		{http.StatusOK, 100, false},
+		{http.StatusNotFound, 0, false},
+	}
+	for _, tt := range tests {
+		var resp = &http.Response{StatusCode: tt.status, ContentLength: tt.contentLength}
+		if got := needsGETFallback(resp); got != tt.want {
+			t.Errorf("needsGETFallback(status=%d, len=%d) = %v, want %v", tt.status, tt.contentLength, got, tt.want)
+		}
+	}
+}
+
+func TestIsRedirectStop(t *testing.T) {
+	var stopped error = &url.Error{Op: "Get", URL: "http://example.com", Err: errDone}
+	if !isRedirectStop(stopped) {
+		t.Error("expected isRedirectStop to recognize errDone")
+	}
+	if isRedirectStop(errors.New("boom")) {
+		t.Error("expected isRedirectStop to reject an unrelated error")
+	}
+}
+
+func TestHeaderListSet(t *testing.T) {
+	var h headerList
+	if err := h.Set("X-Foo: bar"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := h.headers.Get("X-Foo"); got != "bar" {
+		t.Errorf("X-Foo = %q, want bar", got)
+	}
+}
+
+func TestHeaderListSetInvalid(t *testing.T) {
+	var h headerList
+	if err := h.Set("no-colon-here"); err == nil {
+		t.Error("expected an error for a -header value without a colon")
+	}
+}
+
+func TestTestURLHeadForbiddenFallsBackToGET(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	var c http.Client
+	c.CheckRedirect = withMaxRedirects(10, FollowAllRedirects)
+	c.Transport = &recordingTransport{}
+
+	site, err := testURL(&c, server.URL, checkOptions{method: methodAuto})
+	if err != nil {
+		t.Fatalf("testURL: %v", err)
+	}
+	if site.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want 200", site.StatusCode)
+	}
+	if len(site.Redirects) != 0 {
+		t.Errorf("Redirects = %+v, want none - the discarded HEAD probe must not appear", site.Redirects)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple global rate limiter: tokens refill continuously
+// at rate tokens per second, up to capacity, and Wait blocks until a token
+// is available. A rate of 0 disables limiting entirely.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64) *tokenBucket {
+	return &tokenBucket{
+		rate:     rps,
+		capacity: math.Max(rps, 1),
+		tokens:   math.Max(rps, 1),
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) Wait() {
+	if b.rate <= 0 {
+		return
+	}
+	for {
+		b.mu.Lock()
+		var now = time.Now()
+		b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+		time.Sleep(time.Duration(float64(time.Second) / b.rate))
+	}
+}
+
+// hostGate caps the number of concurrent requests in flight to any single
+// host, so one slow or unresponsive domain cannot tie up every worker.
+// A capacity of 0 disables the cap entirely.
+type hostGate struct {
+	capacity int
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+func newHostGate(capacity int) *hostGate {
+	return &hostGate{capacity: capacity, sems: make(map[string]chan struct{})}
+}
+
+func (g *hostGate) acquire(host string) {
+	if g.capacity <= 0 {
+		return
+	}
+	g.mu.Lock()
+	sem, ok := g.sems[host]
+	if !ok {
+		sem = make(chan struct{}, g.capacity)
+		g.sems[host] = sem
+	}
+	g.mu.Unlock()
+	sem <- struct{}{}
+}
+
+func (g *hostGate) release(host string) {
+	if g.capacity <= 0 {
+		return
+	}
+	g.mu.Lock()
+	var sem = g.sems[host]
+	g.mu.Unlock()
+	if sem != nil {
+		<-sem
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketDisabledDoesNotBlock(t *testing.T) {
+	var b = newTokenBucket(0)
+	var done = make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			b.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked with rate limiting disabled")
+	}
+}
+
+func TestNewTokenBucketStartsFull(t *testing.T) {
+	var b = newTokenBucket(5)
+	if b.capacity != 5 {
+		t.Errorf("capacity = %v, want 5", b.capacity)
+	}
+	if b.tokens != 5 {
+		t.Errorf("tokens = %v, want 5", b.tokens)
+	}
+}
+
+func TestHostGateDisabledDoesNotBlock(t *testing.T) {
+	var g = newHostGate(0)
+	g.acquire("example.com")
+	g.acquire("example.com")
+	g.release("example.com")
+	g.release("example.com")
+}
+
+func TestHostGateLimitsConcurrency(t *testing.T) {
+	var g = newHostGate(1)
+	g.acquire("example.com")
+	var acquired = make(chan struct{})
+	go func() {
+		g.acquire("example.com")
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second acquire did not block while the first held the slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.release("example.com")
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second acquire never unblocked after release")
+	}
+}
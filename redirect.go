@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// hopsContextKey is the context key under which testURL stashes the *[]Hop
+// slice that recordingTransport appends to as a request is followed
+// through redirects.
+type hopsContextKey struct{}
+
+// recordingTransport wraps an http.RoundTripper and, for every round trip
+// made on a request carrying a hops slice in its context, appends the
+// response as a Hop before returning it. Since http.Client drives the
+// redirect loop by calling RoundTrip once per hop, this captures every
+// intermediate response, not just the one CheckRedirect ultimately stops
+// on - similar to how gobuster intercepts 3xx responses by wrapping the
+// RoundTripper rather than only inspecting the final response.
+type recordingTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var base = t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if hops, ok := req.Context().Value(hopsContextKey{}).(*[]Hop); ok {
+		*hops = append(*hops, Hop{
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Method:     req.Method,
+			Headers:    resp.Header,
+		})
+	}
+	return resp, err
+}
+
+// withHops returns a copy of req carrying hops so that recordingTransport
+// appends every hop of the eventual redirect chain to it.
+func withHops(req *http.Request, hops *[]Hop) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), hopsContextKey{}, hops))
+}
+
+// splitHops separates the hops recordingTransport captured into the
+// redirect chain worth reporting to the user and the headers of the final
+// response. recordingTransport appends a Hop for every RoundTrip, including
+// the terminal, non-redirected one, but Site.Redirects should only list the
+// hops actually redirected through - the destination itself is already
+// reported as Site.ResponseURL/StatusCode.
+func splitHops(hops []Hop) (chain []Hop, finalHeaders http.Header) {
+	if len(hops) == 0 {
+		return nil, nil
+	}
+	return hops[:len(hops)-1], hops[len(hops)-1].Headers
+}
+
+// withMaxRedirects wraps a Redirector so that it stops following redirects
+// once max hops have been made, regardless of what next would otherwise
+// decide.
+func withMaxRedirects(max int, next Redirector) Redirector {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= max {
+			return errDone
+		}
+		return next(req, via)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error classes surfaced as Site.ErrorClass, distinguishing the kind of
+// transient or permanent network failure that was encountered.
+const (
+	classDNS         = "dns"
+	classTLS         = "tls"
+	classConnRefused = "connection-refused"
+	classConnReset   = "connection-reset"
+	classTimeout     = "timeout"
+	classOther       = "other"
+	retryOnConnReset = "connection-reset"
+)
+
+// classifyError maps a request error onto one of the typed error classes,
+// so automation consuming checkurls output can tell a dead link apart
+// from a transient network hiccup.
+func classifyError(err error) string {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = uerr.Err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return classDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return classConnRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return classConnReset
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return classTimeout
+	}
+	if strings.Contains(err.Error(), "tls:") || strings.Contains(err.Error(), "certificate") {
+		return classTLS
+	}
+	return classOther
+}
+
+// retryOptions configures the retry/backoff subsystem: how many times to
+// retry, the base backoff duration, and which outcomes are worth retrying.
+type retryOptions struct {
+	retries   int
+	backoff   time.Duration
+	statuses  map[int]bool
+	connReset bool
+}
+
+// parseRetryOn parses a comma-separated `-retry-on` value such as
+// "429,502,503,504,connection-reset" into a retryOptions' status/class
+// selection.
+func parseRetryOn(s string) (map[int]bool, bool, error) {
+	var statuses = make(map[int]bool)
+	var connReset bool
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == retryOnConnReset {
+			connReset = true
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid -retry-on entry %q: %w", part, err)
+		}
+		statuses[code] = true
+	}
+	return statuses, connReset, nil
+}
+
+// shouldRetry reports whether site's outcome is one that retry considers
+// worth retrying.
+func shouldRetry(site *Site, retry retryOptions) bool {
+	if site.ErrorClass == classConnReset && retry.connReset {
+		return true
+	}
+	return retry.statuses[site.StatusCode]
+}
+
+// retryAfter looks for a Retry-After header on site's final response and,
+// if present and parseable, returns how long to wait.
+func retryAfter(site *Site) (time.Duration, bool) {
+	if site.FinalHeaders == nil {
+		return 0, false
+	}
+	var value = site.FinalHeaders.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
+
+// checkWithRetry calls testURL, retrying with exponential backoff and
+// jitter when the outcome is classified as retryable, up to retry.retries
+// attempts beyond the first. Retry-After headers on 429/503 responses
+// take precedence over the computed backoff. Every attempt - not just the
+// first - goes through rate and hosts, so a retried host is still subject
+// to the configured -rps and -per-host-concurrency limits rather than
+// bypassing them on every retry.
+func checkWithRetry(c *http.Client, site string, opts checkOptions, retry retryOptions, rate *tokenBucket, hosts *hostGate, host string) (*Site, error) {
+	for attempt := 0; ; attempt++ {
+		rate.Wait()
+		hosts.acquire(host)
+		result, err := testURL(c, site, opts)
+		hosts.release(host)
+		if err != nil || result == nil || attempt >= retry.retries || !shouldRetry(result, retry) {
+			return result, err
+		}
+		var wait = retry.backoff * time.Duration(1<<uint(attempt))
+		if after, ok := retryAfter(result); ok && after > 0 {
+			wait = after
+		}
+		wait += time.Duration(rand.Int63n(int64(retry.backoff) + 1))
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "dns error",
+			err:  &url.Error{Op: "Get", URL: "http://example.invalid", Err: &net.DNSError{Err: "no such host", Name: "example.invalid"}},
+			want: classDNS,
+		},
+		{
+			name: "connection refused",
+			err:  &url.Error{Op: "Get", URL: "http://example.com", Err: syscall.ECONNREFUSED},
+			want: classConnRefused,
+		},
+		{
+			name: "connection reset",
+			err:  &url.Error{Op: "Get", URL: "http://example.com", Err: syscall.ECONNRESET},
+			want: classConnReset,
+		},
+		{
+			name: "timeout",
+			err:  &url.Error{Op: "Get", URL: "http://example.com", Err: fakeTimeoutError{}},
+			want: classTimeout,
+		},
+		{
+			name: "anything else",
+			err:  &url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("boom")},
+			want: classOther,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeTimeoutError implements net.Error with Timeout() == true, without
+// depending on an actual timed-out connection or context.
+type fakeTimeoutError struct{}
+
+func (fakeTimeoutError) Error() string   { return "deadline exceeded" }
+func (fakeTimeoutError) Timeout() bool   { return true }
+func (fakeTimeoutError) Temporary() bool { return true }
+
+func TestParseRetryOn(t *testing.T) {
+	statuses, connReset, err := parseRetryOn("429,502, connection-reset ,503")
+	if err != nil {
+		t.Fatalf("parseRetryOn returned error: %v", err)
+	}
+	if !connReset {
+		t.Error("expected connReset to be true")
+	}
+	for _, code := range []int{429, 502, 503} {
+		if !statuses[code] {
+			t.Errorf("expected status %d to be retryable", code)
+		}
+	}
+	if statuses[504] {
+		t.Error("504 was not in the list and should not be retryable")
+	}
+}
+
+func TestParseRetryOnInvalidEntry(t *testing.T) {
+	if _, _, err := parseRetryOn("429,not-a-code"); err == nil {
+		t.Error("expected an error for an unparseable -retry-on entry")
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	var retry = retryOptions{statuses: map[int]bool{503: true}, connReset: true}
+	if !shouldRetry(&Site{StatusCode: 503}, retry) {
+		t.Error("expected a listed status code to be retryable")
+	}
+	if shouldRetry(&Site{StatusCode: 500}, retry) {
+		t.Error("expected an unlisted status code not to be retryable")
+	}
+	if !shouldRetry(&Site{ErrorClass: classConnReset}, retry) {
+		t.Error("expected a connection-reset error to be retryable when connReset is enabled")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	var site = &Site{FinalHeaders: http.Header{"Retry-After": []string{"5"}}}
+	wait, ok := retryAfter(site)
+	if !ok || wait != 5*time.Second {
+		t.Errorf("retryAfter = %v, %v; want 5s, true", wait, ok)
+	}
+}
+
+func TestRetryAfterMissing(t *testing.T) {
+	if _, ok := retryAfter(&Site{}); ok {
+		t.Error("expected no Retry-After value when FinalHeaders is nil")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRules is the parsed "User-agent: *" group of one origin's
+// robots.txt: which paths are allowed or disallowed, and how long to wait
+// between requests to that origin.
+type robotsRules struct {
+	disallow   []string
+	allow      []string
+	crawlDelay time.Duration
+}
+
+// allowed reports whether path is permitted, using the standard
+// longest-matching-prefix rule between Allow and Disallow entries.
+func (r *robotsRules) allowed(path string) bool {
+	var matchLen = -1
+	var isAllowed = true
+	for _, d := range r.disallow {
+		if strings.HasPrefix(path, d) && len(d) > matchLen {
+			matchLen = len(d)
+			isAllowed = false
+		}
+	}
+	for _, a := range r.allow {
+		if strings.HasPrefix(path, a) && len(a) > matchLen {
+			matchLen = len(a)
+			isAllowed = true
+		}
+	}
+	return isAllowed
+}
+
+// robotsCache fetches and caches robots.txt per origin, and enforces each
+// origin's Crawl-delay between requests. Requests are made with client and
+// opts, so robots.txt fetches honor the same -timeout, -user-agent, and
+// -header settings as every other request checkurls makes.
+type robotsCache struct {
+	client *http.Client
+	opts   checkOptions
+
+	mu    sync.Mutex
+	rules map[string]*robotsRules
+
+	lastMu sync.Mutex
+	last   map[string]time.Time
+}
+
+func newRobotsCache(client *http.Client, opts checkOptions) *robotsCache {
+	return &robotsCache{
+		client: client,
+		opts:   opts,
+		rules:  make(map[string]*robotsRules),
+		last:   make(map[string]time.Time),
+	}
+}
+
+func (c *robotsCache) rulesFor(u *url.URL) *robotsRules {
+	var origin = u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	if r, ok := c.rules[origin]; ok {
+		c.mu.Unlock()
+		return r
+	}
+	c.mu.Unlock()
+	var r = fetchRobots(c.client, c.opts, origin)
+	c.mu.Lock()
+	c.rules[origin] = r
+	c.mu.Unlock()
+	return r
+}
+
+func fetchRobots(client *http.Client, opts checkOptions, origin string) *robotsRules {
+	var rules = &robotsRules{}
+	req, err := http.NewRequest(http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return rules
+	}
+	applyRequestOptions(req, opts)
+	resp, err := client.Do(req)
+	if err != nil {
+		return rules
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rules
+	}
+	var applies bool
+	var scanner = bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+		switch field {
+		case "user-agent":
+			applies = value == "*"
+		case "disallow":
+			if applies && value != "" {
+				rules.disallow = append(rules.disallow, value)
+			}
+		case "allow":
+			if applies && value != "" {
+				rules.allow = append(rules.allow, value)
+			}
+		case "crawl-delay":
+			if applies {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+					rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+	return rules
+}
+
+// wait blocks, if necessary, so that at least delay has passed since the
+// last request this process made to host.
+func (c *robotsCache) wait(host string, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	c.lastMu.Lock()
+	var prev, seen = c.last[host]
+	var now = time.Now()
+	c.last[host] = now
+	c.lastMu.Unlock()
+	if seen {
+		if until := prev.Add(delay); now.Before(until) {
+			time.Sleep(until.Sub(now))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestRobotsRulesAllowed(t *testing.T) {
+	var rules = &robotsRules{
+		disallow: []string{"/private"},
+		allow:    []string{"/private/public"},
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private", false},
+		{"/private/secret", false},
+		{"/private/public", true},
+		{"/private/public/page", true},
+	}
+	for _, tt := range tests {
+		if got := rules.allowed(tt.path); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsRulesNoDisallowAllowsEverything(t *testing.T) {
+	var rules = &robotsRules{}
+	if !rules.allowed("/anything") {
+		t.Error("expected an empty rule set to allow every path")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// stateEntry is the compact per-URL record persisted by a stateStore:
+// enough to decide whether a URL can be skipped on resume and to make a
+// conditional request (If-None-Match / If-Modified-Since) otherwise.
+type stateEntry struct {
+	URL          string    `json:"url"`
+	StatusCode   int       `json:"status_code"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// stateStore persists stateEntry records as JSON lines, one append per
+// check, so that a later run can resume incrementally over a large corpus
+// instead of rechecking every URL from scratch. A nil *stateStore is valid
+// and simply disables all of this - the -state flag is optional.
+type stateStore struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[string]stateEntry
+}
+
+// openStateStore loads any existing records at path and opens it for
+// appending. An empty path disables state tracking.
+func openStateStore(path string) (*stateStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var entries = make(map[string]stateEntry)
+	if f, err := os.Open(path); err == nil {
+		var scanner = bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry stateEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+				entries[entry.URL] = entry
+			}
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &stateStore{file: file, entries: entries}, nil
+}
+
+func (s *stateStore) lookup(url string) (stateEntry, bool) {
+	if s == nil {
+		return stateEntry{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[url]
+	return entry, ok
+}
+
+func (s *stateStore) record(entry stateEntry) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.URL] = entry
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	s.file.Write(data)
+}
+
+func (s *stateStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// conditionalHeaders returns a copy of base with If-None-Match and
+// If-Modified-Since set from entry, so a revisit can be answered with a
+// cheap 304 when the resource has not changed.
+func conditionalHeaders(base http.Header, entry stateEntry) http.Header {
+	var h = base.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	if entry.ETag != "" {
+		h.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		h.Set("If-Modified-Since", entry.LastModified)
+	}
+	return h
+}
+
+// entryFromSite builds the stateEntry to persist for a completed check of
+// site, pulling ETag/Last-Modified from the final response's headers.
+func entryFromSite(site string, s *Site) stateEntry {
+	var entry = stateEntry{URL: site, StatusCode: s.StatusCode, CheckedAt: time.Now()}
+	if s.FinalHeaders != nil {
+		entry.ETag = s.FinalHeaders.Get("ETag")
+		entry.LastModified = s.FinalHeaders.Get("Last-Modified")
+	}
+	return entry
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateStoreRecordAndLookup(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "state.jsonl")
+	store, err := openStateStore(path)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+	defer store.Close()
+	store.record(stateEntry{URL: "http://example.com", StatusCode: 200})
+	entry, ok := store.lookup("http://example.com")
+	if !ok || entry.StatusCode != 200 {
+		t.Errorf("lookup = %+v, %v; want StatusCode 200, true", entry, ok)
+	}
+	if _, ok := store.lookup("http://other.example"); ok {
+		t.Error("expected no entry for a URL that was never recorded")
+	}
+}
+
+func TestStateStoreLoadsExistingEntries(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "state.jsonl")
+	first, err := openStateStore(path)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+	first.record(stateEntry{URL: "http://example.com", StatusCode: 200})
+	first.Close()
+
+	second, err := openStateStore(path)
+	if err != nil {
+		t.Fatalf("openStateStore (reopen): %v", err)
+	}
+	defer second.Close()
+	if entry, ok := second.lookup("http://example.com"); !ok || entry.StatusCode != 200 {
+		t.Errorf("lookup after reopen = %+v, %v; want StatusCode 200, true", entry, ok)
+	}
+}
+
+func TestStateStoreEmptyPathDisablesTracking(t *testing.T) {
+	store, err := openStateStore("")
+	if err != nil {
+		t.Fatalf("openStateStore(\"\"): %v", err)
+	}
+	if store != nil {
+		t.Fatalf("expected a nil store for an empty path, got %+v", store)
+	}
+}
+
+func TestNilStateStoreIsSafe(t *testing.T) {
+	var store *stateStore
+	store.record(stateEntry{URL: "http://example.com"})
+	if _, ok := store.lookup("http://example.com"); ok {
+		t.Error("expected a nil store to report no entries")
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Close on a nil store returned an error: %v", err)
+	}
+}
+
+func TestConditionalHeaders(t *testing.T) {
+	var base = http.Header{"Accept": []string{"*/*"}}
+	var h = conditionalHeaders(base, stateEntry{ETag: `"abc"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"})
+	if got := h.Get("If-None-Match"); got != `"abc"` {
+		t.Errorf("If-None-Match = %q, want %q", got, `"abc"`)
+	}
+	if got := h.Get("If-Modified-Since"); got != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("If-Modified-Since = %q, want the configured last-modified value", got)
+	}
+	if got := base.Get("If-None-Match"); got != "" {
+		t.Error("conditionalHeaders must not mutate the base header set")
+	}
+}
+
+func TestEntryFromSite(t *testing.T) {
+	var s = &Site{
+		StatusCode:   200,
+		FinalHeaders: http.Header{"Etag": []string{`"abc"`}, "Last-Modified": []string{"yesterday"}},
+	}
+	var entry = entryFromSite("http://example.com", s)
+	if entry.URL != "http://example.com" || entry.StatusCode != 200 {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.ETag != `"abc"` || entry.LastModified != "yesterday" {
+		t.Errorf("entry ETag/LastModified = %q/%q, want \"abc\"/yesterday", entry.ETag, entry.LastModified)
+	}
+}
+
+func TestEntryFromSiteWithoutFinalHeaders(t *testing.T) {
+	var entry = entryFromSite("http://example.com", &Site{StatusCode: 0, ErrorClass: classDNS})
+	if entry.ETag != "" || entry.LastModified != "" {
+		t.Errorf("expected no ETag/LastModified when FinalHeaders is nil, got %+v", entry)
+	}
+}